@@ -3,12 +3,15 @@ package main
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +20,12 @@ import (
 	"github.com/google/uuid"
 )
 
+// chunkSize is the amount of raw file data placed into each base64-encoded
+// transfer chunk. CalDAV servers (Nextcloud in particular) routinely reject
+// VEVENTs whose DESCRIPTION grows past a few MB, so pushes are always
+// split regardless of file size.
+const chunkSize = 700 * 1024
+
 type Organizer struct {
 	client       *caldav.Client
 	calendarPath string
@@ -53,27 +62,59 @@ func main() {
 
 func NewOrganizer(serverURL, username, password, calendarName string) (*Organizer, error) {
 	// Create a custom http.Client with Basic Auth
-	appPassword := "admin"
-	backendURL := "http://127.0.0.1" // Base DAV URL
-	calendarPath := "/remote.php/dav/calendars/admin/personal"
-
 	basicAuthTransport := &basicAuthRoundTripper{
 		username: username,
-		password: appPassword,
+		password: password,
 		rt:       http.DefaultTransport,
 	}
 	httpClient := &http.Client{Transport: basicAuthTransport}
-	client, err := caldav.NewClient(httpClient, backendURL)
+	client, err := caldav.NewClient(httpClient, serverURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create CalDAV client: %w", err)
 	}
 
+	calendarPath, err := discoverCalendarPath(context.Background(), client, calendarName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover calendar %q: %w", calendarName, err)
+	}
+
 	return &Organizer{
 		client:       client,
 		calendarPath: calendarPath,
 	}, nil
 }
 
+// discoverCalendarPath resolves the full path of the calendar named
+// calendarName via RFC 5397 (current-user-principal) and RFC 4791
+// (calendar-home-set) discovery, so the organizer works against any
+// CalDAV server (Nextcloud, Radicale, SOGo, Baïkal, iCloud, Google, ...)
+// instead of a single hard-coded path. The caller is expected to cache
+// the returned path rather than re-running discovery on every request.
+func discoverCalendarPath(ctx context.Context, client *caldav.Client, calendarName string) (string, error) {
+	principal, err := client.FindCurrentUserPrincipal(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to find current user principal: %w", err)
+	}
+
+	homeSet, err := client.FindCalendarHomeSet(ctx, principal)
+	if err != nil {
+		return "", fmt.Errorf("failed to find calendar home set: %w", err)
+	}
+
+	calendars, err := client.FindCalendars(ctx, homeSet)
+	if err != nil {
+		return "", fmt.Errorf("failed to list calendars: %w", err)
+	}
+
+	for _, cal := range calendars {
+		if cal.Name == calendarName {
+			return cal.Path, nil
+		}
+	}
+
+	return "", fmt.Errorf("no calendar named %q found under %s", calendarName, homeSet)
+}
+
 func (o *Organizer) InteractiveMode() {
 	scanner := bufio.NewScanner(os.Stdin)
 
@@ -82,7 +123,9 @@ func (o *Organizer) InteractiveMode() {
 	fmt.Println("  exec <cmd>         - Execute on all hosts")
 	fmt.Println("  exec @host:<cmd>   - Execute on specific host")
 	fmt.Println("  exec @*:<cmd>      - Execute on all hosts (explicit)")
-	fmt.Println("  list               - List recent commands")
+	fmt.Println("  push <file> @host <dest_path> - Send a file to a host (chunked)")
+	fmt.Println("  pull <transfer_id> <dest_path> - Assemble a file a host uploaded")
+	fmt.Println("  list               - List recent commands and transfers")
 	fmt.Println("  get <event_id>     - Get command output")
 	fmt.Println("  clear              - Clear executed events")
 	fmt.Println("  exit               - Exit organizer")
@@ -109,6 +152,24 @@ func (o *Organizer) InteractiveMode() {
 			cmd := strings.Join(parts[1:], " ")
 			o.CreateCommand(cmd)
 
+		case "push":
+			if len(parts) < 4 {
+				fmt.Println("Usage: push <file> @host <dest_path>")
+				continue
+			}
+			o.CreateFileTransfer(parts[1], strings.TrimPrefix(parts[2], "@"), parts[3])
+
+		case "pull":
+			if len(parts) < 3 {
+				fmt.Println("Usage: pull <transfer_id> <dest_path>")
+				continue
+			}
+			if err := o.AssembleFile(parts[1], parts[2]); err != nil {
+				fmt.Printf("Error assembling file: %v\n", err)
+			} else {
+				fmt.Printf("File assembled: %s\n", parts[2])
+			}
+
 		case "list":
 			o.ListEvents()
 
@@ -188,6 +249,9 @@ func (o *Organizer) ListEvents() {
 	fmt.Println("ID\t\t\t\tCommand\t\t\tStatus")
 	fmt.Println("--------------------------------------------------------------------------")
 
+	transferChunks := make(map[string]int)
+	transferTotal := make(map[string]int)
+
 	for _, eventData := range events {
 		// Parse the raw iCalendar data string.
 		cal := eventData.Data
@@ -202,6 +266,17 @@ func (o *Organizer) ListEvents() {
 
 		uid := vevent.Props.Get(ical.PropUID).Value
 
+		if transferID, _, _, isManifest, ok := parseChunkSummary(summary); ok {
+			transferChunks[transferID]++
+			if isManifest {
+				// The chunk count is in the manifest's body, not its summary.
+				if m, err := parseManifest(description); err == nil {
+					transferTotal[transferID] = m.total
+				}
+			}
+			continue
+		}
+
 		if strings.HasPrefix(summary, "Meeting from nobody:") {
 			cmd := strings.TrimPrefix(summary, "Meeting from nobody: ")
 			status := "Pending"
@@ -212,6 +287,27 @@ func (o *Organizer) ListEvents() {
 			fmt.Printf("%s\t%s\t\t%s\n", uid, cmd, status)
 		}
 	}
+
+	if len(transferChunks) > 0 {
+		fmt.Println("\nFile Transfers:")
+		fmt.Println("TransferID\t\t\t\tProgress")
+		fmt.Println("--------------------------------------------------------------------------")
+		for transferID, seen := range transferChunks {
+			total, known := transferTotal[transferID]
+			received := seen
+			if known {
+				received-- // the manifest event itself doesn't count as a chunk
+			}
+			status := fmt.Sprintf("%d chunks received", received)
+			if known {
+				status = fmt.Sprintf("In progress (%d/%d chunks)", received, total)
+				if received >= total {
+					status = fmt.Sprintf("Complete (%d/%d chunks)", received, total)
+				}
+			}
+			fmt.Printf("%s\t%s\n", transferID, status)
+		}
+	}
 }
 
 func (o *Organizer) GetEventOutput(eventUID string) {
@@ -267,6 +363,255 @@ func (o *Organizer) GetEventOutput(eventUID string) {
 	}
 }
 
+// transferManifest describes a chunked file transfer, carried in the
+// description of the final "__chunk <transferID> manifest" VEVENT.
+type transferManifest struct {
+	filename string
+	size     int
+	total    int
+	sha256   string
+}
+
+// CreateFileTransfer splits a local file into base64 VEVENT chunks plus a
+// trailing SHA-256 manifest event, then schedules a matching "download"
+// command so targetHost assembles them back into destPath. Pushes are
+// always chunked since large files routinely exceed the DESCRIPTION size
+// CalDAV servers will accept in a single event.
+func (o *Organizer) CreateFileTransfer(filePath, targetHost, destPath string) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", filePath, err)
+		return
+	}
+
+	transferID := uuid.New().String()
+	sum := sha256.Sum256(data)
+	total := (len(data) + chunkSize - 1) / chunkSize
+	if total == 0 {
+		total = 1
+	}
+
+	for seq := 0; seq < total; seq++ {
+		start := seq * chunkSize
+		end := start + chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		encoded := base64.StdEncoding.EncodeToString(data[start:end])
+		if err := o.putTransferEvent(targetHost, fmt.Sprintf("%s %d/%d", transferID, seq+1, total), encoded); err != nil {
+			fmt.Printf("Error uploading chunk %d/%d: %v\n", seq+1, total, err)
+			return
+		}
+	}
+
+	manifest := fmt.Sprintf("Filename: %s\nSize: %d\nChunks: %d\nSHA256: %x", path.Base(filePath), len(data), total, sum)
+	if err := o.putTransferEvent(targetHost, transferID+" manifest", manifest); err != nil {
+		fmt.Printf("Error uploading manifest: %v\n", err)
+		return
+	}
+
+	o.CreateCommand(fmt.Sprintf("@%s:download %s %s", targetHost, transferID, destPath))
+	fmt.Printf("File transfer created: %s\nTransferID: %s\nChunks: %d\nSHA256: %x\n", filePath, transferID, total, sum)
+}
+
+// AssembleFile reassembles a transfer a host uploaded (via the "upload"
+// command) and verifies the manifest's SHA-256 before the file is
+// considered complete. All chunks are fetched into memory by a single
+// query first; they are then written to destPath in sequence order.
+func (o *Organizer) AssembleFile(transferID, destPath string) error {
+	chunks, manifest, err := o.fetchTransfer(transferID)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", destPath, err)
+	}
+	defer out.Close()
+
+	hasher := sha256.New()
+	for seq := 1; seq <= manifest.total; seq++ {
+		encoded, ok := chunks[seq]
+		if !ok {
+			return fmt.Errorf("missing chunk %d/%d for transfer %s", seq, manifest.total, transferID)
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("failed to decode chunk %d: %w", seq, err)
+		}
+		if _, err := out.Write(data); err != nil {
+			return fmt.Errorf("failed to write chunk %d: %w", seq, err)
+		}
+		hasher.Write(data)
+	}
+
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	if sum != manifest.sha256 {
+		out.Close()
+		os.Remove(destPath)
+		return fmt.Errorf("checksum mismatch for %s (got %s, want %s)", destPath, sum, manifest.sha256)
+	}
+
+	return nil
+}
+
+// fetchTransfer queries the calendar for every chunk/manifest VEVENT
+// belonging to transferID and returns the chunk bodies keyed by sequence
+// number alongside the parsed manifest.
+func (o *Organizer) fetchTransfer(transferID string) (map[int]string, *transferManifest, error) {
+	query := &caldav.CalendarQuery{
+		CompFilter: caldav.CompFilter{
+			Name: "VCALENDAR",
+			Comps: []caldav.CompFilter{
+				{
+					Name: "VEVENT",
+					Props: []caldav.PropFilter{
+						{Name: "SUMMARY", TextMatch: &caldav.TextMatch{Text: "__chunk " + transferID}},
+					},
+				},
+			},
+		},
+	}
+
+	events, err := o.client.QueryCalendar(context.Background(), o.calendarPath, query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query transfer chunks: %w", err)
+	}
+
+	chunks := make(map[int]string)
+	var manifest *transferManifest
+
+	for _, eventData := range events {
+		cal := eventData.Data
+		if cal == nil || len(cal.Events()) == 0 {
+			continue
+		}
+		vevent := cal.Events()[0]
+		summary := vevent.Props.Get(ical.PropSummary).Value
+		description := vevent.Props.Get(ical.PropDescription).Value
+		description = strings.ReplaceAll(description, "\\n", "\n")
+
+		id, seq, _, isManifest, ok := parseChunkSummary(summary)
+		if !ok || id != transferID {
+			continue
+		}
+
+		if isManifest {
+			m, err := parseManifest(description)
+			if err != nil {
+				continue
+			}
+			manifest = m
+			continue
+		}
+		chunks[seq] = description
+	}
+
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("manifest not found for transfer %s", transferID)
+	}
+	return chunks, manifest, nil
+}
+
+// putTransferEvent creates a single "__chunk" VEVENT targeted at host
+// carrying suffix ("<seq>/<total>" or "manifest") in its summary and body
+// in its description.
+func (o *Organizer) putTransferEvent(host, suffix, body string) error {
+	uid := uuid.New().String()
+	eventPath := path.Join(o.calendarPath, uid+".ics")
+
+	start := time.Now()
+	end := start.Add(30 * time.Minute)
+
+	event := ical.NewEvent()
+	event.Name = "VEVENT"
+	event.Props.SetText(ical.PropUID, uid)
+	event.Props.SetDateTime(ical.PropDateTimeStamp, time.Now())
+	event.Props.SetDateTime(ical.PropDateTimeStart, start)
+	event.Props.SetDateTime(ical.PropDateTimeEnd, end)
+	event.Props.SetText(ical.PropSummary, fmt.Sprintf("Meeting from nobody: @%s:__chunk %s", host, suffix))
+	event.Props.SetText(ical.PropDescription, body)
+
+	cal := ical.NewCalendar()
+	cal.Props.SetText(ical.PropVersion, "2.0")
+	cal.Props.SetText(ical.PropProductID, "-//MeetC2//Organizer//EN")
+	cal.Children = []*ical.Component{event.Component}
+
+	_, err := o.client.PutCalendarObject(context.Background(), eventPath, cal)
+	return err
+}
+
+// parseChunkSummary extracts the transfer ID and sequence info from a
+// "@host:__chunk <transferID> <seq>/<total>" (or "... manifest") summary.
+// ok is false when summary does not describe a chunk/manifest event.
+func parseChunkSummary(summary string) (transferID string, seq, total int, isManifest, ok bool) {
+	commandLine := strings.TrimSpace(strings.TrimPrefix(summary, "Meeting from nobody:"))
+	if !strings.HasPrefix(commandLine, "@") {
+		return "", 0, 0, false, false
+	}
+	parts := strings.SplitN(commandLine, ":", 2)
+	if len(parts) != 2 {
+		return "", 0, 0, false, false
+	}
+	fields := strings.Fields(parts[1])
+	if len(fields) != 3 || fields[0] != "__chunk" {
+		return "", 0, 0, false, false
+	}
+	if fields[2] == "manifest" {
+		return fields[1], 0, 0, true, true
+	}
+	seq, total, err := parseChunkSeq(fields[2])
+	if err != nil {
+		return "", 0, 0, false, false
+	}
+	return fields[1], seq, total, false, true
+}
+
+// parseChunkSeq parses a "<seq>/<total>" chunk position.
+func parseChunkSeq(s string) (seq, total int, err error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("malformed chunk sequence %q", s)
+	}
+	if seq, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, err
+	}
+	if total, err = strconv.Atoi(parts[1]); err != nil {
+		return 0, 0, err
+	}
+	return seq, total, nil
+}
+
+// parseManifest parses the "Key: value" body of a transfer manifest event.
+func parseManifest(description string) (*transferManifest, error) {
+	m := &transferManifest{}
+	for _, line := range strings.Split(description, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ": ")
+		if !ok {
+			continue
+		}
+		var err error
+		switch key {
+		case "Filename":
+			m.filename = value
+		case "Size":
+			m.size, err = strconv.Atoi(value)
+		case "Chunks":
+			m.total, err = strconv.Atoi(value)
+		case "SHA256":
+			m.sha256 = value
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if m.total == 0 || m.sha256 == "" {
+		return nil, fmt.Errorf("incomplete manifest")
+	}
+	return m, nil
+}
+
 func (o *Organizer) ClearExecutedEvents() {
 	query := &caldav.CalendarQuery{
 		CompFilter: caldav.CompFilter{
